@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHandlersUploadListDelete drives UploadFilesHandler, GetFilesHandler
+// and DeleteFileHandler end-to-end against each BucketClient driver, so the
+// thing chunk0-3 actually asked for (handlers wired to the new interface)
+// has real coverage, not just the BucketClient methods in isolation.
+func TestHandlersUploadListDelete(t *testing.T) {
+	for name, newClient := range bucketClientFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			previous := store
+			store = newClient()
+			t.Cleanup(func() { store = previous })
+
+			uploadBody := `{"data":"data:text/plain;base64,aGVsbG8=","name":"hello.txt"}`
+			uploadReq := httptest.NewRequest(http.MethodPost, "/api/upload_files", bytes.NewBufferString(uploadBody))
+			uploadRec := httptest.NewRecorder()
+			UploadFilesHandler(uploadRec, uploadReq)
+
+			if uploadRec.Code != http.StatusOK {
+				t.Fatalf("upload: got status %d, body %q", uploadRec.Code, uploadRec.Body.String())
+			}
+			var uploadRes UploadFileResponse
+			if err := json.Unmarshal(uploadRec.Body.Bytes(), &uploadRes); err != nil {
+				t.Fatalf("decoding upload response: %v", err)
+			}
+			if uploadRes.ImageUrl == "" {
+				t.Error("expected a non-empty ImageUrl")
+			}
+
+			listReq := httptest.NewRequest(http.MethodGet, "/api/files", nil)
+			listRec := httptest.NewRecorder()
+			GetFilesHandler(listRec, listReq)
+
+			if listRec.Code != http.StatusOK {
+				t.Fatalf("list: got status %d, body %q", listRec.Code, listRec.Body.String())
+			}
+			var items []GetFilesResponseItem
+			if err := json.Unmarshal(listRec.Body.Bytes(), &items); err != nil {
+				t.Fatalf("decoding list response: %v", err)
+			}
+			if len(items) != 1 || items[0].Key != "hello.txt" {
+				t.Fatalf("got items %+v, want a single hello.txt entry", items)
+			}
+
+			deleteReq := httptest.NewRequest(http.MethodPost, "/api/delete_file", bytes.NewBufferString(`{"name":"hello.txt"}`))
+			deleteRec := httptest.NewRecorder()
+			DeleteFileHandler(deleteRec, deleteReq)
+
+			if deleteRec.Code != http.StatusOK {
+				t.Fatalf("delete: got status %d, body %q", deleteRec.Code, deleteRec.Body.String())
+			}
+
+			afterRec := httptest.NewRecorder()
+			GetFilesHandler(afterRec, httptest.NewRequest(http.MethodGet, "/api/files", nil))
+			var afterDelete []GetFilesResponseItem
+			if err := json.Unmarshal(afterRec.Body.Bytes(), &afterDelete); err != nil {
+				t.Fatalf("decoding post-delete list response: %v", err)
+			}
+			if len(afterDelete) != 0 {
+				t.Fatalf("expected file to be deleted, got %+v", afterDelete)
+			}
+		})
+	}
+}
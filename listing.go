@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// listObjects walks every page of the active storage backend's listing for
+// prefix/delimiter and invokes onPage once per page, so callers never need
+// to buffer more than one page of keys at a time.
+func listObjects(ctx context.Context, prefix, delimiter string, onPage func(ListPage) error) error {
+	return store.ListObjects(ctx, prefix, delimiter, onPage)
+}
+
+// streamFiles serves /api/files?stream=1 as Server-Sent Events, flushing
+// each GetFilesResponseItem as soon as its presigned URL is minted.
+func streamFiles(w http.ResponseWriter, r *http.Request, prefix, delimiter string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	err := listObjects(r.Context(), prefix, delimiter, func(page ListPage) error {
+		for _, item := range page.Objects {
+			if strings.HasPrefix(item.Key, thumbnailPrefix) {
+				continue
+			}
+
+			respItem, err := buildFilesResponseItem(r.Context(), item)
+			if err != nil {
+				return err
+			}
+
+			jbytes, err := json.Marshal(respItem)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "data: %s\n\n", jbytes)
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to stream objects: %v\n", err)
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+		flusher.Flush()
+		return
+	}
+
+	fmt.Fprint(w, "event: done\ndata: {}\n\n")
+	flusher.Flush()
+}
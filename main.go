@@ -6,19 +6,20 @@ import (
 	"embed"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/joho/godotenv"
 )
 
+// svc is only populated for the tigris/localstack drivers; S3-specific
+// features (multipart upload, tus) that fall outside the BucketClient
+// abstraction talk to it directly.
 var svc *s3.Client
 
 // Embed the public directory
@@ -30,23 +31,31 @@ func main() {
 	// Load environment variables
 	godotenv.Load()
 
-	// Load AWS SDK configuration
-	sdkConfig, err := config.LoadDefaultConfig(context.TODO())
+	// Select the storage backend (STORAGE_DRIVER=tigris|localstack|fs)
+	var err error
+	store, err = initStorage(context.TODO())
 	if err != nil {
-		log.Printf("Couldn't load default configuration. Here's why: %v\n", err)
+		log.Printf("Couldn't initialize storage backend. Here's why: %v\n", err)
 		return
 	}
 
-	// Create S3 service client
-	svc = s3.NewFromConfig(sdkConfig, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String("https://fly.storage.tigris.dev")
-		o.Region = "auto"
-	})
-
-	// Configure routes and handlers
-	http.HandleFunc("/api/files", GetFilesHandler)
-	http.HandleFunc("/api/upload_files", UploadFilesHandler)
-	http.HandleFunc("/api/delete_file", DeleteFileHandler)
+	// Configure routes and handlers. The API routes below sit behind the
+	// SigV4 middleware so S3-compatible clients (aws-cli, boto3, rclone)
+	// can drive them, not just the embedded web UI. The fs driver's
+	// localObjectsPrefix route is registered separately and is not checked.
+	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("/api/files", GetFilesHandler)
+	apiMux.HandleFunc("/api/upload_files", UploadFilesHandler)
+	apiMux.HandleFunc("/api/delete_file", DeleteFileHandler)
+	apiMux.HandleFunc("/api/upload_init", UploadInitHandler)
+	apiMux.HandleFunc("/api/upload_complete", UploadCompleteHandler)
+	apiMux.HandleFunc("/api/upload_abort", UploadAbortHandler)
+	http.Handle("/api/", sigV4Middleware(apiMux))
+	mountTusHandler()
+
+	if fsStore, ok := store.(*fsBucketClient); ok {
+		http.Handle(localObjectsPrefix, http.StripPrefix(localObjectsPrefix, http.FileServer(http.Dir(fsStore.root))))
+	}
 
 	// Serve static files
 	sub, err := fs.Sub(publicFiles, "public")
@@ -65,20 +74,36 @@ func main() {
 }
 
 type UploadFileRequest struct {
-	Data string `json:"data"`
-	Name string `json:"name"`
+	Data    string `json:"data"`
+	Name    string `json:"name"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Quality int    `json:"quality,omitempty"`
+	Format  string `json:"format,omitempty"`
 }
 
 type UploadFileResponse struct {
-	ImageUrl string `json:"imageUrl"`
+	ImageUrl     string `json:"imageUrl"`
+	ThumbnailUrl string `json:"thumbnailUrl,omitempty"`
 }
 
-// UploadFilesHandler handles the upload of files to Tigris
+// UploadFilesHandler handles the upload of files to Tigris. It is intended
+// for small files only (see largeFileThreshold) since the whole request
+// body is base64-decoded into memory; larger files should go through
+// UploadInitHandler/UploadCompleteHandler instead.
 func UploadFilesHandler(w http.ResponseWriter, r *http.Request) {
+	// Reject large files instead of base64-decoding them into memory; the
+	// caller should use UploadInitHandler/UploadCompleteHandler instead.
+	r.Body = http.MaxBytesReader(w, r.Body, uploadBodyLimit)
+
 	// Parse the request body into the UploadFileRequest struct
 	var req UploadFileRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
+		if err.Error() == "http: request body too large" {
+			http.Error(w, fmt.Sprintf("file exceeds the %d byte limit for this endpoint; use /api/upload_init instead", largeFileThreshold), http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -91,31 +116,36 @@ func UploadFilesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Upload the file to Tigris
-	_, err = svc.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(os.Getenv("BUCKET_NAME")),
-		Key:    aws.String(req.Name),
-		Body:   bytes.NewReader(decode),
-	})
+	// Upload the file to the active storage backend
+	err = store.UploadObject(r.Context(), req.Name, bytes.NewReader(decode))
 	if err != nil {
-		log.Printf("Failed to upload data to S3: %v\n", err)
+		log.Printf("Failed to upload data: %v\n", err)
 	}
 
 	// Generate a presigned URL for the uploaded file
-	presignClient := s3.NewPresignClient(svc)
-	presignedUrl, err := presignClient.PresignGetObject(context.Background(),
-		&s3.GetObjectInput{
-			Bucket: aws.String(os.Getenv("BUCKET_NAME")),
-			Key:    aws.String(req.Name),
-		},
-		s3.WithPresignExpires(time.Hour*1))
+	url, err := store.PresignGet(r.Context(), req.Name, time.Hour*1)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Return the presigned URL in the response
 	res := UploadFileResponse{
-		ImageUrl: presignedUrl.URL,
+		ImageUrl: url,
+	}
+
+	// If the upload is an image, also store a resized thumbnail alongside
+	// the original so galleries don't need to ship full-size originals.
+	thumbnail, ok, err := maybeGenerateThumbnail(decode, req.Width, req.Height, req.Quality, req.Format)
+	if err != nil {
+		log.Printf("Failed to generate thumbnail for %s: %v\n", req.Name, err)
+	} else if ok {
+		thumbnailKey := thumbnailPrefix + req.Name
+		if err := store.UploadObject(r.Context(), thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+			log.Printf("Failed to upload thumbnail: %v\n", err)
+		} else if thumbnailUrl, err := store.PresignGet(r.Context(), thumbnailKey, time.Hour*1); err != nil {
+			log.Printf("Failed to presign thumbnail: %v\n", err)
+		} else {
+			res.ThumbnailUrl = thumbnailUrl
+		}
 	}
 	jbytes, err := json.Marshal(res)
 	if err != nil {
@@ -130,54 +160,44 @@ func UploadFilesHandler(w http.ResponseWriter, r *http.Request) {
 type GetFilesResponseItem struct {
 	Key          string `json:"Key"`
 	Url          string `json:"Url"`
+	ThumbnailUrl string `json:"ThumbnailUrl,omitempty"`
 	LastModified string `json:"LastModified"`
 }
 
-// GetFilesHandler handles the retrieval of files from Tigris
+// GetFilesHandler handles the retrieval of files from Tigris. With
+// ?stream=1 it upgrades to Server-Sent Events and flushes each item as its
+// presigned URL is minted instead of buffering the whole listing in memory.
 func GetFilesHandler(w http.ResponseWriter, r *http.Request) {
-	// Create a request to list objects in the bucket
-	req := &s3.ListObjectsV2Input{
-		Bucket: aws.String(os.Getenv("BUCKET_NAME")),
+	prefix := r.URL.Query().Get("prefix")
+	delimiter := r.URL.Query().Get("delimiter")
+
+	if r.URL.Query().Get("stream") == "1" {
+		streamFiles(w, r, prefix, delimiter)
+		return
 	}
 
-	// Loop through the objects in the bucket
-	isTruncated := true
 	items := []GetFilesResponseItem{}
-	for isTruncated {
-		// List objects in the bucket
-		resp, err := svc.ListObjectsV2(context.TODO(), req)
-		if err != nil {
-			log.Printf("Failed to list objects: %v\n", err)
-			return
-		}
+	err := listObjects(r.Context(), prefix, delimiter, func(page ListPage) error {
+		for _, item := range page.Objects {
+			if strings.HasPrefix(item.Key, thumbnailPrefix) {
+				continue
+			}
 
-		// Generate presigned URLs for each object
-		for _, item := range resp.Contents {
-			presignClient := s3.NewPresignClient(svc)
-			presignedUrl, err := presignClient.PresignGetObject(context.Background(),
-				&s3.GetObjectInput{
-					Bucket: aws.String(os.Getenv("BUCKET_NAME")),
-					Key:    item.Key,
-				},
-				s3.WithPresignExpires(time.Hour*1))
+			respItem, err := buildFilesResponseItem(r.Context(), item)
 			if err != nil {
-				log.Fatal(err)
+				return err
 			}
 
-			// Append the object to the response
-			items = append(items, GetFilesResponseItem{
-				Key:          *item.Key,
-				Url:          presignedUrl.URL,
-				LastModified: item.LastModified.String(),
-			})
-
-			// Update the request to get the next page of objects
-			isTruncated = *resp.IsTruncated
-			req.ContinuationToken = resp.NextContinuationToken
+			items = append(items, respItem)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to list objects: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// Return the response
 	jbytes, err := json.Marshal(items)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -201,16 +221,19 @@ func DeleteFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the file from Tigris
-	_, err = svc.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-		Bucket: aws.String(os.Getenv("BUCKET_NAME")),
-		Key:    aws.String(req.Name),
-	})
+	// Delete the file from the active storage backend
+	err = store.DeleteObject(r.Context(), req.Name)
 	if err != nil {
-		log.Printf("Failed to delete file from S3: %v\n", err)
+		log.Printf("Failed to delete file: %v\n", err)
 		return
 	}
 
+	// Best-effort: clean up the thumbnail too, if one was ever generated,
+	// so it doesn't become orphaned storage.
+	if err := store.DeleteObject(r.Context(), thumbnailPrefix+req.Name); err != nil {
+		log.Printf("Failed to delete thumbnail for %s: %v\n", req.Name, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(`{"message": "ok"}`))
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// largeFileThreshold is the point at which the frontend should switch from
+// the base64 JSON upload path to the presigned multipart flow.
+const largeFileThreshold = 5 * 1024 * 1024 // 5 MiB
+
+// maxMultipartParts is S3's own hard limit on the number of parts a single
+// multipart upload can have.
+const maxMultipartParts = 10000
+
+// uploadBodyLimit bounds the raw request body UploadFilesHandler will
+// accept, enforcing largeFileThreshold despite base64 inflating the file
+// data by roughly a third plus some slack for the surrounding JSON.
+const uploadBodyLimit = largeFileThreshold*4/3 + 1024
+
+// requireS3 rejects the request when the active storage backend isn't
+// S3-compatible, since multipart upload isn't part of the BucketClient
+// abstraction.
+func requireS3(w http.ResponseWriter) bool {
+	if svc == nil {
+		http.Error(w, "multipart upload requires STORAGE_DRIVER=tigris or localstack", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+type UploadInitRequest struct {
+	Name  string `json:"name"`
+	Parts int    `json:"parts"`
+}
+
+type UploadInitResponse struct {
+	UploadId string   `json:"uploadId"`
+	Key      string   `json:"key"`
+	Urls     []string `json:"urls"`
+}
+
+// UploadInitHandler starts an S3 multipart upload against Tigris and hands
+// the caller a presigned PUT URL for each part so the browser can upload
+// directly without routing bytes through this server.
+func UploadInitHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireS3(w) {
+		return
+	}
+
+	var req UploadInitRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Parts < 1 || req.Parts > maxMultipartParts {
+		http.Error(w, fmt.Sprintf("parts must be between 1 and %d", maxMultipartParts), http.StatusBadRequest)
+		return
+	}
+
+	create, err := svc.CreateMultipartUpload(context.TODO(), &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(os.Getenv("BUCKET_NAME")),
+		Key:    aws.String(req.Name),
+	})
+	if err != nil {
+		log.Printf("Failed to create multipart upload: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	presignClient := s3.NewPresignClient(svc)
+	urls := make([]string, req.Parts)
+	for i := 0; i < req.Parts; i++ {
+		partNumber := int32(i + 1)
+		presignedUrl, err := presignClient.PresignUploadPart(context.Background(),
+			&s3.UploadPartInput{
+				Bucket:     aws.String(os.Getenv("BUCKET_NAME")),
+				Key:        aws.String(req.Name),
+				UploadId:   create.UploadId,
+				PartNumber: aws.Int32(partNumber),
+			},
+			s3.WithPresignExpires(time.Hour*1))
+		if err != nil {
+			log.Printf("Failed to presign part %d: %v\n", partNumber, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		urls[i] = presignedUrl.URL
+	}
+
+	res := UploadInitResponse{
+		UploadId: *create.UploadId,
+		Key:      req.Name,
+		Urls:     urls,
+	}
+	jbytes, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jbytes)
+}
+
+type CompletedPartRequest struct {
+	PartNumber int32  `json:"partNumber"`
+	ETag       string `json:"etag"`
+}
+
+type UploadCompleteRequest struct {
+	Name     string                 `json:"name"`
+	UploadId string                 `json:"uploadId"`
+	Parts    []CompletedPartRequest `json:"parts"`
+}
+
+// UploadCompleteHandler assembles the part ETags returned by the browser and
+// finalizes the multipart upload in Tigris.
+func UploadCompleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireS3(w) {
+		return
+	}
+
+	var req UploadCompleteRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sort.Slice(req.Parts, func(i, j int) bool {
+		return req.Parts[i].PartNumber < req.Parts[j].PartNumber
+	})
+
+	completedParts := make([]types.CompletedPart, len(req.Parts))
+	for i, p := range req.Parts {
+		completedParts[i] = types.CompletedPart{
+			ETag:       aws.String(p.ETag),
+			PartNumber: aws.Int32(p.PartNumber),
+		}
+	}
+
+	_, err = svc.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(os.Getenv("BUCKET_NAME")),
+		Key:      aws.String(req.Name),
+		UploadId: aws.String(req.UploadId),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to complete multipart upload: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	presignClient := s3.NewPresignClient(svc)
+	presignedUrl, err := presignClient.PresignGetObject(context.Background(),
+		&s3.GetObjectInput{
+			Bucket: aws.String(os.Getenv("BUCKET_NAME")),
+			Key:    aws.String(req.Name),
+		},
+		s3.WithPresignExpires(time.Hour*1))
+	if err != nil {
+		log.Printf("Failed to presign completed upload: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := UploadFileResponse{
+		ImageUrl: presignedUrl.URL,
+	}
+	jbytes, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jbytes)
+}
+
+type UploadAbortRequest struct {
+	Name     string `json:"name"`
+	UploadId string `json:"uploadId"`
+}
+
+// UploadAbortHandler cleans up a multipart upload that the client gave up
+// on, releasing the parts already stored in Tigris.
+func UploadAbortHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireS3(w) {
+		return
+	}
+
+	var req UploadAbortRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = svc.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(os.Getenv("BUCKET_NAME")),
+		Key:      aws.String(req.Name),
+		UploadId: aws.String(req.UploadId),
+	})
+	if err != nil {
+		log.Printf("Failed to abort multipart upload: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "ok"}`))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ObjectInfo describes a single stored object, independent of whichever
+// BucketClient implementation produced it.
+type ObjectInfo struct {
+	Key          string
+	LastModified time.Time
+}
+
+// ListPage is one page of a ListObjects call.
+type ListPage struct {
+	Objects []ObjectInfo
+}
+
+// BucketClient is the storage backend the HTTP handlers talk to. The Tigris
+// S3 driver (and its LocalStack sibling) is the production implementation;
+// the fs driver exists so the quickstart can be run and integration-tested
+// offline, without any S3-compatible endpoint at all.
+type BucketClient interface {
+	UploadObject(ctx context.Context, key string, body io.Reader) error
+	DownloadObject(ctx context.Context, key string) (io.ReadCloser, error)
+	DeleteObject(ctx context.Context, key string) error
+	ListObjects(ctx context.Context, prefix, delimiter string, onPage func(ListPage) error) error
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// store is the active storage backend, selected in main() via
+// STORAGE_DRIVER.
+var store BucketClient
+
+// initStorage builds the BucketClient named by STORAGE_DRIVER
+// (tigris|localstack|fs), defaulting to tigris. For the tigris and
+// localstack drivers it also populates the package-level svc, since a few
+// S3-specific features (multipart upload, tus) talk to the SDK client
+// directly rather than through the BucketClient abstraction.
+func initStorage(ctx context.Context) (BucketClient, error) {
+	bucket := os.Getenv("BUCKET_NAME")
+
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "tigris":
+		client, err := newS3Client(ctx, "https://fly.storage.tigris.dev", "auto")
+		if err != nil {
+			return nil, err
+		}
+		svc = client
+		return &s3BucketClient{client: client, bucket: bucket}, nil
+	case "localstack":
+		endpoint := os.Getenv("LOCALSTACK_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "http://localhost:4566"
+		}
+		client, err := newS3Client(ctx, endpoint, "us-east-1")
+		if err != nil {
+			return nil, err
+		}
+		svc = client
+		return &s3BucketClient{client: client, bucket: bucket}, nil
+	case "fs":
+		root := os.Getenv("FS_STORAGE_ROOT")
+		if root == "" {
+			root = "./.data"
+		}
+		return newFSBucketClient(root)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// localObjectsPrefix is where fsBucketClient's objects are served from over
+// HTTP so that PresignGet can hand back a URL the frontend can actually
+// fetch, mirroring what a presigned S3 URL would do.
+const localObjectsPrefix = "/api/local_objects/"
+
+// fsBucketClient is a BucketClient backed by a local directory. It exists
+// so the quickstart can be run and handler-tested offline, with no
+// S3-compatible endpoint available at all.
+type fsBucketClient struct {
+	root string
+}
+
+func newFSBucketClient(root string) (*fsBucketClient, error) {
+	root = filepath.Clean(root)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &fsBucketClient{root: root}, nil
+}
+
+// path maps an object key to a file under root, rejecting any key that
+// would escape it.
+func (b *fsBucketClient) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(b.root, cleaned), nil
+}
+
+func (b *fsBucketClient) UploadObject(ctx context.Context, key string, body io.Reader) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, body)
+	return err
+}
+
+func (b *fsBucketClient) DownloadObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// DeleteObject removes key, matching S3's DeleteObject semantics of not
+// erroring when the key never existed.
+func (b *fsBucketClient) DeleteObject(ctx context.Context, key string) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *fsBucketClient) ListObjects(ctx context.Context, prefix, delimiter string, onPage func(ListPage) error) error {
+	var objects []ObjectInfo
+	err := filepath.Walk(b.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		key := filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(p, b.root), "/"))
+		if prefix != "" && !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		if delimiter != "" && strings.Contains(strings.TrimPrefix(key, prefix), delimiter) {
+			return nil
+		}
+
+		objects = append(objects, ObjectInfo{Key: key, LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return onPage(ListPage{Objects: objects})
+}
+
+func (b *fsBucketClient) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return localObjectsPrefix + key, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// newS3Client builds an S3 SDK client pointed at the given S3-compatible
+// endpoint (Tigris or LocalStack).
+func newS3Client(ctx context.Context, endpoint, region string) (*s3.Client, error) {
+	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(sdkConfig, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+		o.Region = region
+		o.UsePathStyle = region != "auto"
+	}), nil
+}
+
+// s3BucketClient is the BucketClient implementation backed by the AWS S3
+// SDK, used for both the Tigris and LocalStack drivers.
+type s3BucketClient struct {
+	client *s3.Client
+	bucket string
+}
+
+func (b *s3BucketClient) UploadObject(ctx context.Context, key string, body io.Reader) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	return err
+}
+
+func (b *s3BucketClient) DownloadObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *s3BucketClient) DeleteObject(ctx context.Context, key string) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (b *s3BucketClient) ListObjects(ctx context.Context, prefix, delimiter string, onPage func(ListPage) error) error {
+	req := &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+	}
+	if prefix != "" {
+		req.Prefix = aws.String(prefix)
+	}
+	if delimiter != "" {
+		req.Delimiter = aws.String(delimiter)
+	}
+
+	for {
+		resp, err := b.client.ListObjectsV2(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		objects := make([]ObjectInfo, 0, len(resp.Contents))
+		for _, item := range resp.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          *item.Key,
+				LastModified: *item.LastModified,
+			})
+		}
+		if err := onPage(ListPage{Objects: objects}); err != nil {
+			return err
+		}
+
+		if resp.IsTruncated == nil || !*resp.IsTruncated {
+			return nil
+		}
+		req.ContinuationToken = resp.NextContinuationToken
+	}
+}
+
+func (b *s3BucketClient) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(b.client)
+	presignedUrl, err := presignClient.PresignGetObject(ctx,
+		&s3.GetObjectInput{
+			Bucket: aws.String(b.bucket),
+			Key:    aws.String(key),
+		},
+		s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return presignedUrl.URL, nil
+}
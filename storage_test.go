@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// bucketClientFactories are exercised by every test in this file. The
+// localstack driver is skipped unless LOCALSTACK_ENDPOINT is set, since it
+// needs a running LocalStack container (see docker-compose.yml).
+func bucketClientFactories(t *testing.T) map[string]func() BucketClient {
+	factories := map[string]func() BucketClient{
+		"fs": func() BucketClient {
+			client, err := newFSBucketClient(t.TempDir())
+			if err != nil {
+				t.Fatalf("newFSBucketClient: %v", err)
+			}
+			return client
+		},
+	}
+
+	if endpoint := os.Getenv("LOCALSTACK_ENDPOINT"); endpoint != "" {
+		factories["localstack"] = func() BucketClient {
+			client, err := newS3Client(context.Background(), endpoint, "us-east-1")
+			if err != nil {
+				t.Fatalf("newS3Client: %v", err)
+			}
+			return &s3BucketClient{client: client, bucket: "quickstart-test"}
+		}
+	}
+
+	return factories
+}
+
+func TestBucketClientUploadDownloadDelete(t *testing.T) {
+	for name, newClient := range bucketClientFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			client := newClient()
+			ctx := context.Background()
+
+			if err := client.UploadObject(ctx, "hello.txt", bytes.NewBufferString("hi there")); err != nil {
+				t.Fatalf("UploadObject: %v", err)
+			}
+
+			r, err := client.DownloadObject(ctx, "hello.txt")
+			if err != nil {
+				t.Fatalf("DownloadObject: %v", err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if string(got) != "hi there" {
+				t.Errorf("got %q, want %q", got, "hi there")
+			}
+
+			if err := client.DeleteObject(ctx, "hello.txt"); err != nil {
+				t.Fatalf("DeleteObject: %v", err)
+			}
+
+			if _, err := client.DownloadObject(ctx, "hello.txt"); err == nil {
+				t.Error("expected DownloadObject to fail after delete")
+			}
+		})
+	}
+}
+
+func TestBucketClientListObjects(t *testing.T) {
+	for name, newClient := range bucketClientFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			client := newClient()
+			ctx := context.Background()
+
+			for _, key := range []string{"a.txt", "b.txt", "nested/c.txt"} {
+				if err := client.UploadObject(ctx, key, bytes.NewBufferString(key)); err != nil {
+					t.Fatalf("UploadObject(%s): %v", key, err)
+				}
+			}
+
+			var keys []string
+			err := client.ListObjects(ctx, "", "", func(page ListPage) error {
+				for _, obj := range page.Objects {
+					keys = append(keys, obj.Key)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ListObjects: %v", err)
+			}
+
+			if len(keys) != 3 {
+				t.Fatalf("got %d keys, want 3: %v", len(keys), keys)
+			}
+		})
+	}
+}
+
+func TestBucketClientPresignGet(t *testing.T) {
+	for name, newClient := range bucketClientFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			client := newClient()
+			ctx := context.Background()
+
+			if err := client.UploadObject(ctx, "presign.txt", bytes.NewBufferString("x")); err != nil {
+				t.Fatalf("UploadObject: %v", err)
+			}
+
+			url, err := client.PresignGet(ctx, "presign.txt", time.Minute)
+			if err != nil {
+				t.Fatalf("PresignGet: %v", err)
+			}
+			if url == "" {
+				t.Error("expected a non-empty presigned URL")
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// thumbnailPrefix is where resized derivatives are stored, alongside the
+// original object.
+const thumbnailPrefix = "thumbnails/"
+
+// defaultThumbnailQuality is used when UploadFileRequest.Quality is unset.
+const defaultThumbnailQuality = 85
+
+// decodableImageTypes are the MIME types maybeGenerateThumbnail can actually
+// decode, matching the codecs registered via the image/jpeg and image/png
+// imports. Anything else detected as image/* (gif, webp, bmp, ...) is left
+// untouched rather than handed to image.Decode, which would just fail.
+var decodableImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+// maybeGenerateThumbnail resizes data into a thumbnail when it looks like an
+// image type we can decode, returning the encoded bytes and whether one was
+// produced. Everything else (non-images, and image formats we don't decode)
+// is left untouched.
+func maybeGenerateThumbnail(data []byte, width, height, quality int, format string) ([]byte, bool, error) {
+	mtype := mimetype.Detect(data)
+	if !decodableImageTypes[mtype.String()] {
+		return nil, false, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding image: %w", err)
+	}
+
+	if width == 0 && height == 0 {
+		width = 256
+	}
+	resized := imaging.Resize(img, width, height, imaging.Lanczos)
+
+	if quality == 0 {
+		quality = defaultThumbnailQuality
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&buf, resized)
+	default:
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("encoding thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), true, nil
+}
+
+// buildFilesResponseItem mints the presigned URLs for a listed object and
+// its possible thumbnail. Presigning is local HMAC math with no network
+// round-trip, so it's cheap to do per item on the page we're already
+// holding; callers fall back to Url when ThumbnailUrl 404s (no thumbnail
+// was ever generated, e.g. non-image uploads). This keeps GetFilesHandler
+// and streamFiles within one page of objects at a time, as chunk0-2
+// intends, instead of front-loading a full bucket scan of thumbnail keys.
+func buildFilesResponseItem(ctx context.Context, item ObjectInfo) (GetFilesResponseItem, error) {
+	url, err := store.PresignGet(ctx, item.Key, time.Hour*1)
+	if err != nil {
+		return GetFilesResponseItem{}, err
+	}
+
+	thumbnailUrl, err := store.PresignGet(ctx, thumbnailPrefix+item.Key, time.Hour*1)
+	if err != nil {
+		return GetFilesResponseItem{}, err
+	}
+
+	return GetFilesResponseItem{
+		Key:          item.Key,
+		Url:          url,
+		ThumbnailUrl: thumbnailUrl,
+		LastModified: item.LastModified.String(),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
+	"github.com/tus/tusd/v2/pkg/memorylocker"
+	"github.com/tus/tusd/v2/pkg/s3store"
+)
+
+// tusMaxUploadSize is the per-upload quota enforced in the pre-create hook.
+const tusMaxUploadSize = 5 * 1024 * 1024 * 1024 // 5 GiB
+
+// newTusHandler builds a resumable tus.io upload endpoint backed by the
+// same Tigris S3 client as the rest of the app, giving the quickstart real
+// resumable uploads for flaky mobile connections, which the base64 JSON
+// handler can't support at all.
+func newTusHandler() (http.Handler, error) {
+	if svc == nil {
+		return nil, fmt.Errorf("tus upload requires STORAGE_DRIVER=tigris or localstack")
+	}
+
+	bucket := os.Getenv("BUCKET_NAME")
+	s3Store := s3store.New(bucket, svc)
+
+	composer := tusd.NewStoreComposer()
+	s3Store.UseIn(composer)
+	memorylocker.New().UseIn(composer)
+
+	// NotifyCompleteUploads is left at its default (false): nothing reads
+	// the handler's CompleteUploads channel, and PreFinishResponseCallback
+	// already handles the finish response, so enabling it would just block
+	// the first completed upload with no consumer draining the channel.
+	return tusd.NewHandler(tusd.Config{
+		BasePath:                  "/files/",
+		StoreComposer:             composer,
+		PreUploadCreateCallback:   tusPreUploadCreateCallback,
+		PreFinishResponseCallback: newTusPreFinishResponseCallback(bucket),
+	})
+}
+
+// tusPreUploadCreateCallback rejects uploads without a filename and
+// enforces tusMaxUploadSize before any bytes are accepted.
+func tusPreUploadCreateCallback(hook tusd.HookEvent) (tusd.HTTPResponse, tusd.FileInfoChanges, error) {
+	if hook.Upload.MetaData["filename"] == "" {
+		return tusd.HTTPResponse{}, tusd.FileInfoChanges{}, fmt.Errorf("filename metadata is required")
+	}
+	if hook.Upload.Size > tusMaxUploadSize {
+		return tusd.HTTPResponse{}, tusd.FileInfoChanges{}, fmt.Errorf("upload exceeds the %d byte quota", tusMaxUploadSize)
+	}
+	return tusd.HTTPResponse{}, tusd.FileInfoChanges{}, nil
+}
+
+// newTusPreFinishResponseCallback returns a hook that replies with the same
+// UploadFileResponse shape the base64 JSON handler uses, so the frontend
+// doesn't need to special-case tus uploads once they've finished.
+func newTusPreFinishResponseCallback(bucket string) func(tusd.HookEvent) (tusd.HTTPResponse, error) {
+	return func(hook tusd.HookEvent) (tusd.HTTPResponse, error) {
+		presignClient := s3.NewPresignClient(svc)
+		presignedUrl, err := presignClient.PresignGetObject(context.Background(),
+			&s3.GetObjectInput{
+				Bucket: aws.String(bucket),
+				Key:    aws.String(hook.Upload.Storage["Key"]),
+			},
+			s3.WithPresignExpires(time.Hour*1))
+		if err != nil {
+			return tusd.HTTPResponse{}, err
+		}
+
+		jbytes, err := json.Marshal(UploadFileResponse{ImageUrl: presignedUrl.URL})
+		if err != nil {
+			return tusd.HTTPResponse{}, err
+		}
+
+		return tusd.HTTPResponse{
+			StatusCode: http.StatusOK,
+			Body:       string(jbytes),
+			Header:     tusd.HTTPHeader{"Content-Type": "application/json"},
+		}, nil
+	}
+}
+
+// mountTusHandler wires /files/ into the default mux, logging and
+// continuing without it if the active backend doesn't support it.
+func mountTusHandler() {
+	handler, err := newTusHandler()
+	if err != nil {
+		log.Printf("tus upload disabled: %v\n", err)
+		return
+	}
+	http.Handle("/files/", handler)
+}